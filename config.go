@@ -0,0 +1,141 @@
+// Config replaces the old compiled-in constants with a JSON file loaded at
+// startup, plus a "scenario" section that declaratively maps inbound
+// events to outbound Responses so testers can script GUI behavior without
+// recompiling. The file is reloadable via SIGHUP.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config is the full set of user-configurable server behavior.
+type Config struct {
+	// IP and Port of the machine running this code
+	ServerAddr string `json:"server_addr"`
+
+	// IP and Port of the processors RPC server
+	ProcessorAddr string `json:"processor_addr"`
+
+	// Label that gets updated with the current time on every tick
+	TestLabel string `json:"test_label"`
+
+	// How often TestLabel is updated, in milliseconds
+	TickerIntervalMs int `json:"ticker_interval_ms"`
+
+	// Buttons that should not immediately toggle visual state
+	DontToggleButtons []string `json:"dont_toggle_buttons"`
+
+	// Declarative rules for scripted GUI behavior
+	Scenario []ScenarioRule `json:"scenario"`
+}
+
+// ScenarioRule maps a single inbound event, e.g. "on Button Btn_Mute
+// press", to the outbound Response(s) it should trigger, e.g. "send
+// SetState 1 to Btn_Mute and SetText MUTED to Lbl_Status".
+type ScenarioRule struct {
+	On        ScenarioTrigger `json:"on"`
+	Responses []Response      `json:"responses"`
+}
+
+// ScenarioTrigger matches against an inbound Rx. Action and Value are
+// optional filters; an empty field matches any value.
+type ScenarioTrigger struct {
+	Type   string `json:"type"` // "Button" or "Slider"
+	Object string `json:"object"`
+	Action string `json:"action,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		ServerAddr:        "192.168.253.8:8080",
+		ProcessorAddr:     "192.168.253.254:8081",
+		TestLabel:         "Lbl_Time",
+		TickerIntervalMs:  20,
+		DontToggleButtons: []string{"Btn_NoTog"},
+	}
+}
+
+// TickerInterval returns the configured label tick interval.
+// Non xi processors start to bug out at 10ms or lower.
+func (c *Config) TickerInterval() time.Duration {
+	if c.TickerIntervalMs <= 0 {
+		return 20 * time.Millisecond
+	}
+	return time.Duration(c.TickerIntervalMs) * time.Millisecond
+}
+
+// Match returns the first scenario rule matching an inbound event of the
+// given type, if any.
+func (c *Config) Match(eventType string, rx Rx) (ScenarioRule, bool) {
+	for _, rule := range c.Scenario {
+		if rule.On.Type != eventType || rule.On.Object != rx.Name {
+			continue
+		}
+		if rule.On.Action != "" && rule.On.Action != rx.Action {
+			continue
+		}
+		if rule.On.Value != "" && rule.On.Value != rx.Value {
+			continue
+		}
+		return rule, true
+	}
+	return ScenarioRule{}, false
+}
+
+var (
+	configMu sync.RWMutex
+	config   = defaultConfig()
+)
+
+// currentConfig returns the active, live-reloadable config.
+func currentConfig() *Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// loadConfig reads and parses the config file at path, starting from the
+// defaults so a partial file only overrides what it sets.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := defaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// watchConfigReload reloads the config file from path every time the
+// process receives SIGHUP, so testers can iterate on GUI behavior without
+// recompiling.
+func watchConfigReload(path string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	for range sighup {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			fmt.Println("Error reloading config:", err)
+			continue
+		}
+
+		configMu.Lock()
+		config = cfg
+		configMu.Unlock()
+
+		fmt.Println("Reloaded config from", path)
+	}
+}
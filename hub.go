@@ -0,0 +1,58 @@
+// Hub fans out every Response the server generates (label ticks, button
+// toggles, slider fills) to any number of subscribed WebSocket clients, so
+// a browser-based test dashboard can observe the exact frames being sent
+// to the processor in real time.
+
+package main
+
+import "sync"
+
+// hub owns the subscriber set; it is the only thing allowed to touch it,
+// which keeps concurrent writes to a closed/hijacked connection from ever
+// happening.
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Response]bool
+}
+
+var wsHub = &hub{
+	subscribers: make(map[chan Response]bool),
+}
+
+// subscribe registers a new subscriber channel and returns it.
+func (h *hub) subscribe() chan Response {
+	ch := make(chan Response, 16)
+
+	h.mu.Lock()
+	h.subscribers[ch] = true
+	h.mu.Unlock()
+
+	return ch
+}
+
+// unsubscribe removes and closes a subscriber channel.
+func (h *hub) unsubscribe(ch chan Response) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish fans a Response out to every current subscriber. A subscriber
+// whose buffer is full misses this frame rather than blocking the
+// publisher; it stays subscribed.
+func (h *hub) publish(response Response) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- response:
+		default:
+			// Subscriber isn't keeping up, drop the frame for it.
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Prometheus instrumentation for inbound requests and the processor
+// connection, registered on the same mux as the rest of the API.
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	inboundRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_echo_inbound_requests_total",
+		Help: "Total inbound requests, by object type.",
+	}, []string{"type"})
+
+	processorWriteLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_echo_processor_write_latency_seconds",
+		Help:    "Time to write a single frame to the processor connection. The client is fire-and-forget and never reads a reply, so this is write latency, not round-trip time.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	processorConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "test_echo_processor_connected",
+		Help: "1 if the processor TCP connection is currently up, 0 otherwise.",
+	})
+
+	processorReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "test_echo_processor_reconnects_total",
+		Help: "Total number of processor dial attempts after the initial connection.",
+	})
+
+	processorDroppedFrames = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "test_echo_processor_dropped_frames_total",
+		Help: "Total number of outbound frames dropped because the send queue was full.",
+	})
+)
+
+func registerMetricsHandler() {
+	http.Handle("/metrics", promhttp.Handler())
+}
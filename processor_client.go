@@ -0,0 +1,261 @@
+// ProcessorClient owns the TCP connection to the processor on its own
+// goroutine: it dials, keeps the socket alive, redials with backoff on
+// failure, and drains an outbound queue so a stalled processor can never
+// block the 20ms ticker.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	processorDialTimeout  = 5 * time.Second
+	processorWriteTimeout = 2 * time.Second
+	processorSendQueueLen = 64
+	processorMaxBackoff   = 30 * time.Second
+)
+
+// ProcessorClient is safe for concurrent use. Callers only ever see Send
+// and Close; the connection itself is never exposed.
+type ProcessorClient struct {
+	addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	sendQueue chan Response
+	closeCh   chan struct{}
+	closeOnce sync.Once
+
+	// pending holds a frame read ahead of its turn while coalescing label
+	// ticks, so drain never discards a distinct command. Only touched from
+	// the single run/drain goroutine.
+	pending *Response
+}
+
+// NewProcessorClient starts the connection goroutine and returns a client
+// ready to accept Send calls.
+func NewProcessorClient(addr string) *ProcessorClient {
+	c := &ProcessorClient{
+		addr:      addr,
+		sendQueue: make(chan Response, processorSendQueueLen),
+		closeCh:   make(chan struct{}),
+	}
+
+	go c.run()
+
+	return c
+}
+
+func (c *ProcessorClient) run() {
+	backoff := time.Second
+	first := true
+
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		default:
+		}
+
+		if !first {
+			processorReconnects.Inc()
+		}
+		first = false
+
+		conn, err := c.dial()
+		if err != nil {
+			slog.Error("processor dial failed", "object", c.addr, "function", "ProcessorClient.dial", "error", err)
+			processorConnected.Set(0)
+			backoff = c.sleepBackoff(backoff)
+			continue
+		}
+
+		backoff = time.Second
+		c.drain(conn)
+	}
+}
+
+func (c *ProcessorClient) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, processorDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	}
+
+	slog.Info("processor connected", "object", c.addr, "function", "ProcessorClient.dial",
+		"local_addr", conn.LocalAddr().String(), "remote_addr", conn.RemoteAddr().String())
+	processorConnected.Set(1)
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	return conn, nil
+}
+
+// drain writes queued frames to conn until a write fails or Close is called.
+func (c *ProcessorClient) drain(conn net.Conn) {
+	defer c.closeConn(conn)
+
+	for {
+		response, ok := c.nextFrame()
+		if !ok {
+			return
+		}
+		response = c.coalesceLabelTicks(response)
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			slog.Error("marshaling queued response failed", "object", c.addr, "function", "ProcessorClient.drain", "error", err)
+			continue
+		}
+
+		start := time.Now()
+		conn.SetWriteDeadline(start.Add(processorWriteTimeout))
+		err = writeFrame(conn, data)
+		processorWriteLatency.Observe(time.Since(start).Seconds())
+		if err != nil {
+			slog.Error("processor write failed", "object", c.addr, "function", "ProcessorClient.drain", "error", err)
+			return
+		}
+	}
+}
+
+// nextFrame returns the next frame to send, preferring one stashed by a
+// previous coalesceLabelTicks pass over reading the queue.
+func (c *ProcessorClient) nextFrame() (Response, bool) {
+	if c.pending != nil {
+		response := *c.pending
+		c.pending = nil
+		return response, true
+	}
+
+	select {
+	case <-c.closeCh:
+		return Response{}, false
+	case response := <-c.sendQueue:
+		return response, true
+	}
+}
+
+// coalesceLabelTicks collapses consecutive queued updates to the ticker
+// label into the most recent one, so a slow processor doesn't fall further
+// and further behind. Everything else - button toggles, slider fills,
+// scenario/RPC commands - is a distinct, one-off frame and is never
+// dropped; the first non-tick frame found behind response is stashed in
+// pending instead of being discarded.
+func (c *ProcessorClient) coalesceLabelTicks(response Response) Response {
+	if !isTestLabelTick(response) {
+		return response
+	}
+
+	for {
+		select {
+		case next := <-c.sendQueue:
+			if !isTestLabelTick(next) {
+				c.pending = &next
+				return response
+			}
+			processorDroppedFrames.Inc()
+			response = next
+		default:
+			return response
+		}
+	}
+}
+
+// isTestLabelTick reports whether response is a periodic update to the
+// configured ticker label, the only kind of frame safe to coalesce.
+func isTestLabelTick(response Response) bool {
+	return response.Type == "Label" && response.Function == "SetText" && response.Object == currentConfig().TestLabel
+}
+
+func writeFrame(conn net.Conn, data []byte) error {
+	req, err := http.NewRequest("POST", "/", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return req.Write(conn)
+}
+
+func (c *ProcessorClient) closeConn(conn net.Conn) {
+	conn.Close()
+	processorConnected.Set(0)
+
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+}
+
+// sleepBackoff sleeps for d plus jitter, then returns the next (doubled,
+// capped) backoff duration.
+func (c *ProcessorClient) sleepBackoff(d time.Duration) time.Duration {
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+
+	select {
+	case <-time.After(d + jitter):
+	case <-c.closeCh:
+	}
+
+	next := d * 2
+	if next > processorMaxBackoff {
+		next = processorMaxBackoff
+	}
+	return next
+}
+
+// Send enqueues response for delivery. If the queue is full, the oldest
+// pending frame is dropped to make room so a stalled processor cannot
+// block the caller.
+func (c *ProcessorClient) Send(ctx context.Context, response Response) error {
+	select {
+	case c.sendQueue <- response:
+		return nil
+	default:
+	}
+
+	select {
+	case <-c.sendQueue:
+		processorDroppedFrames.Inc()
+	default:
+	}
+
+	select {
+	case c.sendQueue <- response:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the connection goroutine and releases the socket.
+func (c *ProcessorClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
@@ -0,0 +1,164 @@
+// Record-and-replay turns the echo server into a deterministic test
+// harness: a real touch-panel session can be captured once with -record
+// and replayed as many times as needed with -replay to reproduce a bug,
+// or diffed in CI against a golden Response stream.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordedEvent is one inbound Rx captured with the wall-clock time it
+// arrived, so a replay can reproduce the original inter-event delays.
+type recordedEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Endpoint  string    `json:"endpoint"`
+	Rx        Rx        `json:"rx"`
+}
+
+var (
+	recordMu   sync.Mutex
+	recordFile *os.File
+)
+
+// startRecording opens path for append; every inbound Rx is written to it
+// as JSONL until the process exits.
+func startRecording(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	recordMu.Lock()
+	recordFile = f
+	recordMu.Unlock()
+
+	return nil
+}
+
+// recordEvent appends one inbound Rx to the record file, if recording is
+// enabled.
+func recordEvent(endpoint string, rx Rx) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+
+	if recordFile == nil {
+		return
+	}
+
+	data, err := json.Marshal(recordedEvent{Timestamp: time.Now(), Endpoint: endpoint, Rx: rx})
+	if err != nil {
+		slog.Error("marshaling recorded event failed", "function", "recordEvent", "error", err)
+		return
+	}
+
+	recordFile.Write(append(data, '\n'))
+}
+
+// replayFile reads a JSONL file written by startRecording and re-injects
+// each event through the same handler pipeline used for live traffic,
+// preserving the original inter-event delays.
+func replayFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var events []recordedEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event recordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for i, event := range events {
+		if i > 0 {
+			time.Sleep(event.Timestamp.Sub(events[i-1].Timestamp))
+		}
+		replayEvent(event)
+	}
+
+	return nil
+}
+
+// scenarioEventTypes maps a replayed endpoint to the event type used to
+// match it against config.Scenario, mirroring the handler registered for
+// that endpoint in main.
+var scenarioEventTypes = map[string]string{
+	"/api/v1/button": "Button",
+	"/api/v1/slider": "Slider",
+}
+
+// replayEvent re-injects one recorded Rx through the same handler used for
+// live traffic. Scenario-matched events already reach the processor via
+// dispatchScenario; for the common, unmatched case the handler only writes
+// its reply into the discarded recorder, so that reply is forwarded to the
+// processor here too, giving the golden-diff stream frames for every
+// replayed event instead of just the scripted ones.
+func replayEvent(event recordedEvent) {
+	body, err := json.Marshal(event.Rx)
+	if err != nil {
+		slog.Error("marshaling replayed event failed", "function", "replayEvent", "error", err)
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodPost, event.Endpoint, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	eventType, ok := scenarioEventTypes[event.Endpoint]
+	if !ok {
+		slog.Warn("unknown endpoint in replay file", "object", event.Endpoint, "function", "replayEvent")
+		return
+	}
+
+	switch event.Endpoint {
+	case "/api/v1/button":
+		replyButtonHandler(rec, req)
+	case "/api/v1/slider":
+		replySliderHandler(rec, req)
+	}
+
+	if _, matched := currentConfig().Match(eventType, event.Rx); matched {
+		// dispatchScenario already sent this event's responses to the
+		// processor; forwarding the recorder too would duplicate them.
+		return
+	}
+
+	forwardReplayReply(event.Endpoint, rec)
+}
+
+// forwardReplayReply sends the Response a replayed handler wrote to its
+// recorder on to the processor, so replay produces the same outbound
+// traffic a live session would.
+func forwardReplayReply(endpoint string, rec *httptest.ResponseRecorder) {
+	if rec.Body.Len() == 0 {
+		return
+	}
+
+	var response Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		slog.Error("unmarshaling replayed reply failed", "object", endpoint, "function", "forwardReplayReply", "error", err)
+		return
+	}
+
+	if err := processor.Send(context.Background(), response); err != nil {
+		slog.Error("forwarding replayed reply to processor failed", "object", endpoint, "function", "forwardReplayReply", "error", err)
+	}
+}
@@ -0,0 +1,227 @@
+// JSON-RPC 2.0 transport for the same API the ad-hoc handlers expose.
+// Lets callers batch and correlate requests with an id instead of relying
+// on the stateless one-shot Response structs, and makes it possible to
+// drive the server with plain curl.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const jsonRPCVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	rpcErrParse          = -32700
+	rpcErrInvalidRequest = -32600
+	rpcErrMethodNotFound = -32601
+	rpcErrInvalidParams  = -32602
+)
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// jsonRPCMethod handles the params of a single named method and returns the
+// value to place in the result field of the response.
+type jsonRPCMethod func(params json.RawMessage) (interface{}, error)
+
+// Service object methods are registered under here, e.g. "Button.Toggle".
+var jsonRPCMethods = map[string]jsonRPCMethod{
+	"Button.Toggle":  rpcButtonToggle,
+	"Slider.SetFill": rpcSliderSetFill,
+	"Label.SetText":  rpcLabelSetText,
+}
+
+// jsonRPCHandler dispatches a single request object or, per the spec, a
+// batch sent as a JSON array. Notifications (requests with no id) are
+// still executed but produce no response frame; a batch made up entirely
+// of notifications gets no body at all.
+func jsonRPCHandler(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeRPCError(w, nil, rpcErrParse, "Parse error")
+		return
+	}
+
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []jsonRPCRequest
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			writeRPCError(w, nil, rpcErrParse, "Parse error")
+			return
+		}
+		if len(reqs) == 0 {
+			writeRPCError(w, nil, rpcErrInvalidRequest, "Invalid Request")
+			return
+		}
+
+		var responses []jsonRPCResponse
+		for _, req := range reqs {
+			if resp, ok := handleRPCRequest(req); ok {
+				responses = append(responses, resp)
+			}
+		}
+
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeRPCError(w, nil, rpcErrParse, "Parse error")
+		return
+	}
+
+	resp, ok := handleRPCRequest(req)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleRPCRequest runs a single decoded request and returns the response
+// frame for it. The second return value is false for notifications (no
+// id), which per spec must not produce a response.
+func handleRPCRequest(req jsonRPCRequest) (jsonRPCResponse, bool) {
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcErrInvalidRequest, "Invalid Request"), req.ID != nil
+	}
+
+	method, ok := jsonRPCMethods[req.Method]
+	if !ok {
+		return rpcErrorResponse(req.ID, rpcErrMethodNotFound, fmt.Sprintf("Method not found: %s", req.Method)), req.ID != nil
+	}
+
+	result, err := method(req.Params)
+	if err != nil {
+		return rpcErrorResponse(req.ID, rpcErrInvalidParams, err.Error()), req.ID != nil
+	}
+
+	if req.ID == nil {
+		return jsonRPCResponse{}, false
+	}
+	return jsonRPCResponse{JSONRPC: jsonRPCVersion, ID: req.ID, Result: result}, true
+}
+
+// writeRPCError replies with a top-level error, for failures (bad JSON,
+// empty batch) that happen before a request id is even available.
+func writeRPCError(w http.ResponseWriter, id json.RawMessage, code int, message string) {
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(rpcErrorResponse(id, code, message))
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) jsonRPCResponse {
+	return jsonRPCResponse{JSONRPC: jsonRPCVersion, ID: id, Error: &jsonRPCError{Code: code, Message: message}}
+}
+
+type buttonToggleParams struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func rpcButtonToggle(params json.RawMessage) (interface{}, error) {
+	var p buttonToggleParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	reply, err := btnVisStateToggle(Rx{Name: p.Name, Value: p.Value})
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		// Name is in DontToggleButtons, nothing to report back.
+		return Response{}, nil
+	}
+
+	var response Response
+	if err := json.Unmarshal(reply, &response); err != nil {
+		return nil, err
+	}
+
+	if err := processor.Send(context.Background(), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+type sliderSetFillParams struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func rpcSliderSetFill(params json.RawMessage) (interface{}, error) {
+	var p sliderSetFillParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	response := Response{
+		Type:     "Slider",
+		Object:   p.Name,
+		Function: "SetFill",
+		Arg1:     p.Value,
+	}
+	wsHub.publish(response)
+
+	if err := processor.Send(context.Background(), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+type labelSetTextParams struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+func rpcLabelSetText(params json.RawMessage) (interface{}, error) {
+	var p labelSetTextParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	response := Response{
+		Type:     "Label",
+		Object:   p.Name,
+		Function: "SetText",
+		Arg1:     p.Text,
+	}
+	wsHub.publish(response)
+
+	if err := processor.Send(context.Background(), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -1,223 +1,246 @@
-// Very basic echo server for testing https://github.com/mefranklin6/Extron-Frontend-API
-// This code is not intended for production use.
-// 1. Server will toggle buttons not in the dontToggleButtons list
-// 2. Server will update a label once per second with the current time
-// 3. Server will update a slider when a slider event is received
-
-package main
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net"
-	"net/http"
-	"slices"
-	"sync"
-	"time"
-)
-
-// Begin User Variables //
-
-// IP and Port of the machine running this code
-const ServerAddr = "192.168.253.8:8080"
-
-// IP and Port of the processors RPC server
-const ProcessorAddr = "192.168.253.254:8081"
-
-// Label that gets update once per second
-const testLabel = "Lbl_Time"
-
-// List of buttons that should not immidately toggle visual state
-var dontToggleButtons = []string{"Btn_NoTog"}
-
-// End User Variables //
-
-const contentType = "application/json"
-
-func main() {
-	// Handle unsolicited data from the processor
-	http.HandleFunc("/api/v1/button", replyButtonHandler)
-	http.HandleFunc("/api/v1/slider", replySliderHandler)
-	http.HandleFunc("/api/v1/test", replyTestHandler)
-
-	// Send a test label periodically
-
-	go startTicker()
-
-	fmt.Println("Starting server at", ServerAddr)
-	if err := http.ListenAndServe(ServerAddr, nil); err != nil {
-		fmt.Println("Error starting server:", err)
-	}
-}
-
-var conn net.Conn
-var connErr error
-var connMu sync.Mutex
-
-// Getter function for the TCP connection
-func getConn() (net.Conn, error) {
-	connMu.Lock()
-	defer connMu.Unlock()
-
-	if conn == nil {
-		fmt.Println("No existing connection.  Attempting to establish.")
-		conn, connErr = net.Dial("tcp", ProcessorAddr)
-		if connErr != nil {
-			return nil, connErr
-		} else {
-			fmt.Println("Connected", conn.LocalAddr().String(), ">", conn.RemoteAddr().String())
-		}
-	}
-
-	return conn, nil
-}
-
-type Response struct {
-	Type     string `json:"type"`
-	Object   string `json:"object"`
-	Function string `json:"function"`
-	Arg1     string `json:"arg1"`
-	Arg2     string `json:"arg2"`
-	Arg3     string `json:"arg3"`
-}
-
-type Rx struct {
-	Name   string `json:"name"`
-	Action string `json:"action"`
-	Value  string `json:"value,omitempty"`
-}
-
-func startTicker() {
-	// Non xi processors start to bug out at 10ms or lower
-	ticker := time.NewTicker(20 * time.Millisecond)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		sendTestSetLabel()
-	}
-}
-
-func replyTestHandler(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte("OK"))
-}
-
-func btnVisStateToggle(rx Rx) ([]byte, error) {
-	if slices.Contains(dontToggleButtons, rx.Name) {
-		return nil, nil
-	}
-
-	state := "0"
-	if rx.Value == "0" {
-		state = "1"
-	}
-
-	response := Response{
-		Type:     "Button",
-		Object:   rx.Name,
-		Function: "SetState",
-		Arg1:     state,
-	}
-	fmt.Print(response)
-
-	return json.Marshal(response)
-}
-
-func replyButtonHandler(w http.ResponseWriter, r *http.Request) {
-	var rx Rx
-	if err := json.NewDecoder(r.Body).Decode(&rx); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
-		return
-	}
-	fmt.Print(rx)
-
-	reply, err := btnVisStateToggle(rx)
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", contentType)
-	w.Write(reply)
-}
-
-func replySliderHandler(w http.ResponseWriter, r *http.Request) {
-	var rx Rx
-	if err := json.NewDecoder(r.Body).Decode(&rx); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
-		return
-	}
-
-	fmt.Print(rx)
-	name := rx.Name
-	value := rx.Value
-
-	response := Response{
-		Type:     "Slider",
-		Object:   name,
-		Function: "SetFill",
-		Arg1:     value,
-	}
-
-	fmt.Print(response)
-
-	reply, err := json.Marshal(response)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", contentType)
-	w.Write(reply)
-}
-
-func sendTestSetLabel() {
-	response := Response{
-		Type:     "Label",
-		Object:   testLabel,
-		Function: "SetText",
-		Arg1:     time.Now().Format("15:04:05.00"),
-	}
-
-	data_to_send, err := json.Marshal(response)
-	if err != nil {
-		fmt.Println("Error creating response:", err)
-		return
-	}
-
-	sendToProcessor(data_to_send)
-}
-
-func sendToProcessor(data_to_send []byte) {
-	conn, err := getConn()
-	if err != nil {
-		fmt.Println("Error connecting to client:", err)
-		return
-	}
-
-	// Create an HTTP request
-	req, err := http.NewRequest("POST", "/", bytes.NewBuffer(data_to_send))
-	if err != nil {
-		fmt.Println("Error creating HTTP request:", err)
-		return
-	}
-	req.Header.Set("Content-Type", contentType)
-
-	// Write the HTTP request to the connection
-	err = req.Write(conn)
-	if err != nil {
-		fmt.Println("Error sending HTTP request:", err)
-		closeConn() // Close and set conn to nil
-		return
-	}
-}
-
-func closeConn() {
-	connMu.Lock()
-	defer connMu.Unlock()
-	if conn != nil {
-		conn.Close()
-		conn = nil
-	}
-}
+// Very basic echo server for testing https://github.com/mefranklin6/Extron-Frontend-API
+// This code is not intended for production use.
+// 1. Server will toggle buttons not in the DontToggleButtons list
+// 2. Server will update a label every ticker interval (20ms by default) with the current time
+// 3. Server will update a slider when a slider event is received
+// Behavior is driven by a JSON config/scenario file, see config.go.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+const contentType = "application/json"
+
+func main() {
+	configPath := flag.String("config", "config.json", "path to the config/scenario file")
+	recordPath := flag.String("record", "", "append every inbound event to this JSONL file")
+	replayPath := flag.String("replay", "", "replay a JSONL file captured with -record against a live processor, then exit")
+	flag.Parse()
+
+	if cfg, err := loadConfig(*configPath); err != nil {
+		fmt.Println("Error loading config, using defaults:", err)
+	} else {
+		config = cfg
+	}
+	go watchConfigReload(*configPath)
+
+	if *recordPath != "" {
+		if err := startRecording(*recordPath); err != nil {
+			fmt.Println("Error opening record file:", err)
+		}
+	}
+
+	// Handle unsolicited data from the processor
+	http.HandleFunc("/api/v1/button", replyButtonHandler)
+	http.HandleFunc("/api/v1/slider", replySliderHandler)
+	http.HandleFunc("/api/v1/test", replyTestHandler)
+
+	// JSON-RPC 2.0 transport, same channel, id-correlated and batchable
+	http.HandleFunc("/api/v1/rpc", jsonRPCHandler)
+
+	// Live feed of every Response sent out, for a test dashboard
+	http.HandleFunc("/ws", wsHandler)
+
+	registerMetricsHandler()
+
+	processor = NewProcessorClient(currentConfig().ProcessorAddr)
+	defer processor.Close()
+
+	if *replayPath != "" {
+		if err := replayFile(*replayPath); err != nil {
+			fmt.Println("Error replaying file:", err)
+		}
+		return
+	}
+
+	// Send a test label periodically
+	go startTicker()
+
+	fmt.Println("Starting server at", currentConfig().ServerAddr)
+	if err := http.ListenAndServe(currentConfig().ServerAddr, nil); err != nil {
+		fmt.Println("Error starting server:", err)
+	}
+}
+
+// processor owns the TCP connection to the Extron processor.
+var processor *ProcessorClient
+
+var requestSeq atomic.Uint64
+
+// nextRequestID returns a process-unique id for correlating a single
+// inbound request across log lines.
+func nextRequestID() string {
+	return fmt.Sprintf("req-%d", requestSeq.Add(1))
+}
+
+type Response struct {
+	Type     string `json:"type"`
+	Object   string `json:"object"`
+	Function string `json:"function"`
+	Arg1     string `json:"arg1"`
+	Arg2     string `json:"arg2"`
+	Arg3     string `json:"arg3"`
+}
+
+type Rx struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Value  string `json:"value,omitempty"`
+}
+
+func startTicker() {
+	ticker := time.NewTicker(currentConfig().TickerInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sendTestSetLabel()
+	}
+}
+
+func replyTestHandler(w http.ResponseWriter, r *http.Request) {
+	inboundRequests.WithLabelValues("test").Inc()
+	w.Write([]byte("OK"))
+}
+
+func btnVisStateToggle(rx Rx) ([]byte, error) {
+	if slices.Contains(currentConfig().DontToggleButtons, rx.Name) {
+		return nil, nil
+	}
+
+	state := "0"
+	if rx.Value == "0" {
+		state = "1"
+	}
+
+	response := Response{
+		Type:     "Button",
+		Object:   rx.Name,
+		Function: "SetState",
+		Arg1:     state,
+	}
+	slog.Info("button toggled", "object", response.Object, "function", "btnVisStateToggle", "state", response.Arg1)
+	wsHub.publish(response)
+
+	return json.Marshal(response)
+}
+
+func replyButtonHandler(w http.ResponseWriter, r *http.Request) {
+	reqID := nextRequestID()
+
+	var rx Rx
+	if err := json.NewDecoder(r.Body).Decode(&rx); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	inboundRequests.WithLabelValues("button").Inc()
+	slog.Info("inbound button event", "request_id", reqID, "object", rx.Name, "function", "replyButtonHandler")
+	recordEvent("/api/v1/button", rx)
+
+	if rule, ok := currentConfig().Match("Button", rx); ok {
+		dispatchScenario(w, rule)
+		return
+	}
+
+	reply, err := btnVisStateToggle(rx)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(reply)
+}
+
+func replySliderHandler(w http.ResponseWriter, r *http.Request) {
+	reqID := nextRequestID()
+
+	var rx Rx
+	if err := json.NewDecoder(r.Body).Decode(&rx); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	inboundRequests.WithLabelValues("slider").Inc()
+	slog.Info("inbound slider event", "request_id", reqID, "object", rx.Name, "function", "replySliderHandler")
+	recordEvent("/api/v1/slider", rx)
+
+	if rule, ok := currentConfig().Match("Slider", rx); ok {
+		dispatchScenario(w, rule)
+		return
+	}
+
+	response := Response{
+		Type:     "Slider",
+		Object:   rx.Name,
+		Function: "SetFill",
+		Arg1:     rx.Value,
+	}
+
+	wsHub.publish(response)
+
+	reply, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(reply)
+}
+
+// dispatchScenario publishes every Response in a matched scenario rule to
+// the websocket hub and replies to the triggering request with the first
+// one; the rest are sent to the processor. Each response reaches the
+// processor exactly once: rule.Responses[0] goes out as the HTTP reply,
+// the same channel the baseline handlers use to deliver commands, so it
+// is not sent again here.
+func dispatchScenario(w http.ResponseWriter, rule ScenarioRule) {
+	for _, response := range rule.Responses {
+		wsHub.publish(response)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if len(rule.Responses) == 0 {
+		return
+	}
+
+	for _, response := range rule.Responses[1:] {
+		if err := processor.Send(context.Background(), response); err != nil {
+			fmt.Println("Error queuing scenario response:", err)
+		}
+	}
+
+	reply, err := json.Marshal(rule.Responses[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(reply)
+}
+
+func sendTestSetLabel() {
+	reqID := nextRequestID()
+
+	response := Response{
+		Type:     "Label",
+		Object:   currentConfig().TestLabel,
+		Function: "SetText",
+		Arg1:     time.Now().Format("15:04:05.00"),
+	}
+	wsHub.publish(response)
+
+	if err := processor.Send(context.Background(), response); err != nil {
+		slog.Error("queuing response for processor failed", "request_id", reqID, "object", response.Object, "function", "sendTestSetLabel", "error", err)
+	}
+}
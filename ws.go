@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	// Test dashboard is served from anywhere during development.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsHandler upgrades the connection and streams every published Response
+// to it until the client disconnects.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Println("Error upgrading to websocket:", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := wsHub.subscribe()
+	defer wsHub.unsubscribe(ch)
+
+	for response := range ch {
+		if err := conn.WriteJSON(response); err != nil {
+			fmt.Println("Error writing to websocket client:", err)
+			return
+		}
+	}
+}